@@ -0,0 +1,155 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"bytes"
+	"math"
+)
+
+// addOnGuard is the module pattern (0=white,1=black) that separates the
+// main EAN-13 symbol from a trailing EAN-5/EAN-2 supplemental add-on.
+var addOnGuard = []byte{1, 0, 1, 1}
+
+// addOnSep is the inter-character separator between add-on digits.
+var addOnSep = []byte{0, 1}
+
+// ean5Parity maps an EAN-5 add-on checksum (sum of odd-position digits×3
+// plus even-position digits×9, mod 10) to the L/G parity pattern used to
+// encode its five digits.
+var ean5Parity = map[int]string{
+	0: "GGLLL",
+	1: "GLGLL",
+	2: "GLLGL",
+	3: "GLLLG",
+	4: "LGGLL",
+	5: "LLGGL",
+	6: "LLLGG",
+	7: "LGLGL",
+	8: "LGLLG",
+	9: "LLGLG",
+}
+
+// ean2Parity maps an EAN-2 add-on value mod 4 to the L/G parity pattern
+// used to encode its two digits.
+var ean2Parity = []string{"LL", "LG", "GL", "GG"}
+
+// scanAddOn looks for an EAN-5 or EAN-2 supplemental add-on in raw, the
+// portion of the scanline following the main symbol's right guard. It
+// returns nil if no add-on is present or it can not be decoded.
+func (dec *Decoder) scanAddOn(raw []byte, mod float64) []int {
+	if mod <= 0 {
+		return nil
+	}
+
+	bars := dec.linearize(raw)
+	if len(bars) == 0 || bars[0].Col != 0 {
+		// the add-on, if any, is preceded by a quiet (white) zone.
+		return nil
+	}
+
+	guard, next, ok := readModules(bars, 1, mod, len(addOnGuard))
+	if !ok || !bytes.Equal(guard, addOnGuard) {
+		return nil
+	}
+
+	for _, n := range []int{5, 2} {
+		digits, parity, ok := decodeAddOnDigits(bars, next, mod, n)
+		if !ok {
+			continue
+		}
+		if !validAddOnParity(digits, parity) {
+			continue
+		}
+		return digits
+	}
+	return nil
+}
+
+// decodeAddOnDigits decodes n add-on digits (7 modules each, separated by
+// addOnSep) starting at bars[start], returning the decoded digits and the
+// L/G parity pattern used to encode them.
+func decodeAddOnDigits(bars []Bar, start int, mod float64, n int) ([]int, string, bool) {
+	var (
+		digits = make([]int, 0, n)
+		parity = make([]byte, 0, n)
+		i      = start
+	)
+	for k := 0; k < n; k++ {
+		if k > 0 {
+			sep, next, ok := readModules(bars, i, mod, len(addOnSep))
+			if !ok || !bytes.Equal(sep, addOnSep) {
+				return nil, "", false
+			}
+			i = next
+		}
+
+		mods, next, ok := readModules(bars, i, mod, 7)
+		if !ok {
+			return nil, "", false
+		}
+		i = next
+
+		switch {
+		case isL(mods):
+			digits = append(digits, codeL[string(mods)])
+			parity = append(parity, 'L')
+		case isG(mods):
+			digits = append(digits, codeG[string(mods)])
+			parity = append(parity, 'G')
+		default:
+			return nil, "", false
+		}
+	}
+	return digits, string(parity), true
+}
+
+func isL(v []byte) bool { _, ok := codeL[string(v)]; return ok }
+func isG(v []byte) bool { _, ok := codeG[string(v)]; return ok }
+
+// validAddOnParity reports whether the decoded digits' checksum matches
+// the L/G parity pattern actually observed while decoding them.
+func validAddOnParity(digits []int, parity string) bool {
+	switch len(digits) {
+	case 5:
+		sum := 0
+		for i, d := range digits {
+			if i%2 == 0 {
+				sum += d * 3
+			} else {
+				sum += d * 9
+			}
+		}
+		return ean5Parity[sum%10] == parity
+	case 2:
+		v := digits[0]*10 + digits[1]
+		return ean2Parity[v%4] == parity
+	default:
+		return false
+	}
+}
+
+// readModules consumes bars starting at bars[start], expanding each into
+// its rounded module count, until n modules have been read. It returns the
+// modules read, the index of the first unconsumed bar, and whether enough
+// bars were available.
+func readModules(bars []Bar, start int, mod float64, n int) ([]byte, int, bool) {
+	out := make([]byte, 0, n)
+	i := start
+	for len(out) < n {
+		if i >= len(bars) {
+			return nil, i, false
+		}
+		cnt := int(math.Round(float64(bars[i].len()) / mod))
+		if cnt <= 0 {
+			cnt = 1
+		}
+		for j := 0; j < cnt && len(out) < n; j++ {
+			out = append(out, byte(bars[i].Col))
+		}
+		i++
+	}
+	return out, i, true
+}
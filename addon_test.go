@@ -0,0 +1,90 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanAddOn(t *testing.T) {
+	const mod = 2 // module width, in pixels.
+
+	for _, tc := range []struct {
+		name   string
+		digits []int
+	}{
+		{
+			name:   "ean5",
+			digits: []int{5, 1, 9, 9, 9},
+		},
+		{
+			name:   "ean2",
+			digits: []int{1, 2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := encodeAddOn(tc.digits, mod)
+
+			dec := NewDecoder()
+			got := dec.scanAddOn(raw, mod)
+			if !reflect.DeepEqual(got, tc.digits) {
+				t.Fatalf("invalid add-on:\ngot= %v\nwant=%v", got, tc.digits)
+			}
+		})
+	}
+}
+
+// encodeAddOn renders digits as a raw (quiet-zone-prefixed) EAN-5/EAN-2
+// add-on scanline, for use by tests.
+func encodeAddOn(digits []int, mod float64) []byte {
+	var parity string
+	switch len(digits) {
+	case 5:
+		sum := 0
+		for i, d := range digits {
+			if i%2 == 0 {
+				sum += d * 3
+			} else {
+				sum += d * 9
+			}
+		}
+		parity = ean5Parity[sum%10]
+	case 2:
+		parity = ean2Parity[(digits[0]*10+digits[1])%4]
+	}
+
+	modules := append([]byte{}, addOnGuard...)
+	for i, d := range digits {
+		if i > 0 {
+			modules = append(modules, addOnSep...)
+		}
+		tbl := codeG
+		if parity[i] == 'L' {
+			tbl = codeL
+		}
+		for k, v := range tbl {
+			if v == d {
+				modules = append(modules, []byte(k)...)
+				break
+			}
+		}
+	}
+
+	raw := make([]byte, 0, 20+len(modules)*int(mod))
+	for i := 0; i < 20; i++ {
+		raw = append(raw, whiteBar)
+	}
+	for _, m := range modules {
+		v := byte(whiteBar)
+		if m == 1 {
+			v = blackBar
+		}
+		for k := 0; k < int(mod); k++ {
+			raw = append(raw, v)
+		}
+	}
+	return raw
+}
@@ -61,8 +61,9 @@ func scan(fname string, dbg bool) {
 
 func debug(src image.Image, dec *isbn.Decoder) {
 	var (
-		red  = color.RGBA{R: 255, A: 255}
-		blue = color.RGBA{B: 255, A: 255}
+		red    = color.RGBA{R: 255, A: 255}
+		blue   = color.RGBA{B: 255, A: 255}
+		yellow = color.RGBA{R: 255, G: 255, A: 255}
 	)
 
 	b := src.Bounds()
@@ -91,6 +92,12 @@ func debug(src image.Image, dec *isbn.Decoder) {
 	patch(tst, mid-40, dec.Guards[2][1])
 	patch(tst, mid-60, dec.Guards[2][2])
 
+	if dec.Region != [4]image.Point{} {
+		for i, p := range dec.Region {
+			drawLine(tst, p, dec.Region[(i+1)%len(dec.Region)], yellow)
+		}
+	}
+
 	o, err := os.Create("out.png")
 	if err != nil {
 		log.Fatalf("could not create output file: %+v", err)
@@ -115,3 +122,40 @@ func patch(img draw.Image, y int, bar isbn.Bar) {
 		}
 	}
 }
+
+// drawLine draws a straight line from p0 to p1 using Bresenham's algorithm.
+func drawLine(img draw.Image, p0, p1 image.Point, c color.Color) {
+	dx, dy := abs(p1.X-p0.X), -abs(p1.Y-p0.Y)
+	sx, sy := 1, 1
+	if p0.X >= p1.X {
+		sx = -1
+	}
+	if p0.Y >= p1.Y {
+		sy = -1
+	}
+	err := dx + dy
+
+	x, y := p0.X, p0.Y
+	for {
+		img.Set(x, y, c)
+		if x == p1.X && y == p1.Y {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,184 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// EncodeOptions configures Encode and EncodeString.
+type EncodeOptions struct {
+	ModuleWidth int // ModuleWidth is the width of a single module, in pixels.
+	Height      int // Height is the barcode height, in pixels.
+	QuietZone   int // QuietZone is the width of the quiet zone on either side, in modules.
+}
+
+// defaultEncodeOptions are the options used for any field left at its zero
+// value.
+var defaultEncodeOptions = EncodeOptions{
+	ModuleWidth: 2,
+	Height:      80,
+	QuietZone:   9,
+}
+
+func (opts EncodeOptions) withDefaults() EncodeOptions {
+	if opts.ModuleWidth <= 0 {
+		opts.ModuleWidth = defaultEncodeOptions.ModuleWidth
+	}
+	if opts.Height <= 0 {
+		opts.Height = defaultEncodeOptions.Height
+	}
+	if opts.QuietZone <= 0 {
+		opts.QuietZone = defaultEncodeOptions.QuietZone
+	}
+	return opts
+}
+
+// Encode renders digits as an EAN-13 barcode image. digits must hold
+// either the 12 digits of an ISBN without its check digit, or all 13
+// including a check digit matching Barcode.Validate.
+func Encode(digits []int, opts ...EncodeOptions) (image.Image, error) {
+	var o EncodeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o = o.withDefaults()
+
+	bc, err := checkDigits(digits)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode barcode: %w", err)
+	}
+
+	modules, err := encodeModules(bc)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode barcode: %w", err)
+	}
+
+	return renderModules(modules, o), nil
+}
+
+// EncodeString renders isbn (a hyphenated or plain, 10- or 13-digit ISBN)
+// as an EAN-13 barcode image.
+func EncodeString(isbn string, opts ...EncodeOptions) (image.Image, error) {
+	digits, err := parseISBN(isbn)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode ISBN %q: %w", isbn, err)
+	}
+	return Encode(digits, opts...)
+}
+
+// checkDigits returns a 13-digit EAN-13 barcode for digits, computing its
+// check digit if digits holds only 12, or verifying it if digits holds 13.
+func checkDigits(digits []int) (Barcode, error) {
+	switch len(digits) {
+	case 12:
+		ds := append(append([]int{}, digits...), 0)
+		sum := 0
+		for i, d := range ds[:12] {
+			if i%2 == 0 {
+				sum += d
+			} else {
+				sum += d * 3
+			}
+		}
+		ds[12] = (10 - sum%10) % 10
+		return Barcode{Symbology: EAN13, Digits: ds}, nil
+	case 13:
+		bc := Barcode{Symbology: EAN13, Digits: append([]int{}, digits...)}
+		if err := bc.Validate(); err != nil {
+			return Barcode{}, err
+		}
+		return bc, nil
+	default:
+		return Barcode{}, fmt.Errorf("invalid digit count: got=%d, want=12 or 13", len(digits))
+	}
+}
+
+// encodeModules renders a 13-digit EAN-13 barcode as its full module
+// sequence (0=white,1=black), including guards.
+func encodeModules(bc Barcode) ([]byte, error) {
+	digits := bc.Digits
+	parity, ok := digitToParity[digits[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid first digit: %d", digits[0])
+	}
+
+	modules := make([]byte, 0, 3+6*7+5+6*7+3)
+	modules = append(modules, 1, 0, 1) // left guard: b,w,b
+
+	for i, d := range digits[1:7] {
+		tbl := codeGByDigit
+		if parity[i] == 'L' {
+			tbl = codeLByDigit
+		}
+		if d < 0 || d > 9 || tbl[d] == nil {
+			return nil, fmt.Errorf("invalid digit: %d", d)
+		}
+		modules = append(modules, tbl[d]...)
+	}
+
+	modules = append(modules, 0, 1, 0, 1, 0) // middle guard: w,b,w,b,w
+
+	for _, d := range digits[7:13] {
+		if d < 0 || d > 9 || codeRByDigit[d] == nil {
+			return nil, fmt.Errorf("invalid digit: %d", d)
+		}
+		modules = append(modules, codeRByDigit[d]...)
+	}
+
+	modules = append(modules, 1, 0, 1) // right guard: b,w,b
+
+	return modules, nil
+}
+
+// renderModules rasterizes a module sequence (0=white,1=black) as a
+// grayscale barcode image.
+func renderModules(modules []byte, opts EncodeOptions) image.Image {
+	width := len(modules)*opts.ModuleWidth + 2*opts.QuietZone*opts.ModuleWidth
+	img := image.NewGray(image.Rect(0, 0, width, opts.Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	x := opts.QuietZone * opts.ModuleWidth
+	for _, m := range modules {
+		if m == 1 {
+			rect := image.Rect(x, 0, x+opts.ModuleWidth, opts.Height)
+			draw.Draw(img, rect, &image.Uniform{C: color.Black}, image.Point{}, draw.Src)
+		}
+		x += opts.ModuleWidth
+	}
+	return img
+}
+
+// parseISBN parses a hyphenated or plain ISBN-10/ISBN-13 string into its
+// digits (with 'X'/'x' read as 10), dropping an ISBN-10's own check digit
+// and prepending the 978 Bookland prefix so Encode can recompute an
+// EAN-13 check digit for it.
+func parseISBN(s string) ([]int, error) {
+	var digits []int
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case r >= '0' && r <= '9':
+			digits = append(digits, int(r-'0'))
+		case (r == 'X' || r == 'x') && len(digits) == 9:
+			digits = append(digits, 10)
+		default:
+			return nil, fmt.Errorf("invalid character %q", r)
+		}
+	}
+
+	switch len(digits) {
+	case 10:
+		return append([]int{9, 7, 8}, digits[:9]...), nil
+	case 12, 13:
+		return digits, nil
+	default:
+		return nil, fmt.Errorf("invalid ISBN length: got=%d digits", len(digits))
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bc   Barcode
+	}{
+		{name: "gopl", bc: Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}}},
+		{name: "another-prefix", bc: Barcode{Symbology: EAN13, Digits: []int{9, 7, 9, 8, 6, 0, 7, 9, 2, 7, 4, 1, 7}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			img, err := Encode(tc.bc.Digits, EncodeOptions{ModuleWidth: 3, Height: 40})
+			if err != nil {
+				t.Fatalf("could not encode: %+v", err)
+			}
+
+			got, err := Scan(img)
+			if err != nil {
+				t.Fatalf("could not scan encoded barcode: %+v", err)
+			}
+			if want := tc.bc; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid round-trip:\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeComputesCheckDigit(t *testing.T) {
+	// 12 digits, no check digit: Encode should compute it and produce a
+	// barcode that validates.
+	digits := []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4}
+
+	img, err := Encode(digits)
+	if err != nil {
+		t.Fatalf("could not encode: %+v", err)
+	}
+
+	bc, err := Scan(img)
+	if err != nil {
+		t.Fatalf("could not scan encoded barcode: %+v", err)
+	}
+	if err := bc.Validate(); err != nil {
+		t.Fatalf("invalid checksum: %+v", err)
+	}
+	want := Barcode{Symbology: EAN13, Digits: append(append([]int{}, digits...), 0)}
+	if !reflect.DeepEqual(bc, want) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", bc, want)
+	}
+}
+
+func TestEncodeInvalidCheckDigit(t *testing.T) {
+	digits := []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 9}
+	if _, err := Encode(digits); err == nil {
+		t.Fatalf("expected an error encoding a barcode with an invalid checksum")
+	}
+}
+
+func TestEncodeString(t *testing.T) {
+	img, err := EncodeString("978-0-13-419044-0")
+	if err != nil {
+		t.Fatalf("could not encode: %+v", err)
+	}
+
+	got, err := Scan(img)
+	if err != nil {
+		t.Fatalf("could not scan encoded barcode: %+v", err)
+	}
+	want := Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import "fmt"
+
+// Validate verifies bc's check digit, per its Symbology's rule: EAN-13
+// weights its digits 1,3,1,3,... starting from the first; UPC-A and EAN-8
+// weight them 3,1,3,1,... instead (UPC-A's weights are, in effect, EAN-13's
+// applied as if its implicit leading zero were still present).
+func (bc Barcode) Validate() error {
+	return specFor(bc.Symbology).checksum(bc.Digits)
+}
+
+func validateEAN13(digits []int) error {
+	if len(digits) != 13 {
+		return fmt.Errorf("isbn: invalid barcode length: got=%d, want=13", len(digits))
+	}
+	return weightedChecksum(digits, 1, 3)
+}
+
+func validateUPCA(digits []int) error {
+	if len(digits) != 12 {
+		return fmt.Errorf("isbn: invalid barcode length: got=%d, want=12", len(digits))
+	}
+	return weightedChecksum(digits, 3, 1)
+}
+
+func validateEAN8(digits []int) error {
+	if len(digits) != 8 {
+		return fmt.Errorf("isbn: invalid barcode length: got=%d, want=8", len(digits))
+	}
+	return weightedChecksum(digits, 3, 1)
+}
+
+// weightedChecksum reports whether digits, weighted alternately starting
+// with firstWeight at the first digit, sum to a multiple of 10.
+func weightedChecksum(digits []int, firstWeight, otherWeight int) error {
+	sum := 0
+	for i, d := range digits {
+		if d < 0 || d > 9 {
+			return fmt.Errorf("isbn: invalid digit at position %d: %d", i, d)
+		}
+		if i%2 == 0 {
+			sum += d * firstWeight
+		} else {
+			sum += d * otherWeight
+		}
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("isbn: invalid checksum: sum=%d is not a multiple of 10", sum)
+	}
+	return nil
+}
+
+// group0RegistrantRanges maps the 8-digit registrant+publication element of
+// an ISBN-13 (everything between the single group digit and the final
+// check digit) to the length of its registrant (publisher) element, for
+// registration group 0 (English).
+var group0RegistrantRanges = []struct {
+	lo, hi int
+	n      int
+}{
+	{0, 19999999, 2},
+	{20000000, 69999999, 3},
+	{70000000, 84999999, 4},
+	{85000000, 89999999, 5},
+	{90000000, 94999999, 6},
+	{95000000, 99999999, 7},
+}
+
+// ISBN13 returns the canonical, hyphenated ISBN-13 form of the barcode,
+// e.g. "978-0-13-419044-0".
+//
+// Hyphenation of the registrant (publisher) element is only known
+// precisely for registration group 0 (English); for any other group,
+// ISBN13 falls back to hyphenating just the EAN prefix, the group and the
+// check digit.
+func (bc Barcode) ISBN13() string {
+	digits := bc.Digits
+	if len(digits) != 13 {
+		return digitsString(digits)
+	}
+
+	prefix := digitsString(digits[:3])
+	group := digitsString(digits[3:4])
+	rest := digits[4:12]
+	check := digitsString(digits[12:])
+
+	if digits[3] == 0 {
+		n := 0
+		for _, d := range rest {
+			n = n*10 + d
+		}
+		for _, r := range group0RegistrantRanges {
+			if n < r.lo || n > r.hi {
+				continue
+			}
+			pub := digitsString(rest[:r.n])
+			title := digitsString(rest[r.n:])
+			return prefix + "-" + group + "-" + pub + "-" + title + "-" + check
+		}
+	}
+
+	return prefix + "-" + group + "-" + digitsString(rest) + "-" + check
+}
+
+// ISBN10 converts a 978-prefixed ISBN-13 barcode back to its 10-digit
+// ISBN-10 form, recomputing the mod-11 check character ('X' stands for 10).
+// Barcodes with a 979 prefix have no ISBN-10 equivalent.
+func (bc Barcode) ISBN10() (string, error) {
+	if len(bc.Digits) != 13 {
+		return "", fmt.Errorf("isbn: invalid barcode length: got=%d, want=13", len(bc.Digits))
+	}
+	if bc.Digits[0] != 9 || bc.Digits[1] != 7 || bc.Digits[2] != 8 {
+		return "", fmt.Errorf("isbn: can not convert non-978-prefixed barcode to ISBN-10")
+	}
+
+	digits := bc.Digits[3:12]
+	sum := 0
+	for i, d := range digits {
+		sum += d * (10 - i)
+	}
+	chk := (11 - sum%11) % 11
+
+	out := make([]byte, 0, 10)
+	for _, d := range digits {
+		out = append(out, byte('0'+d))
+	}
+	if chk == 10 {
+		out = append(out, 'X')
+	} else {
+		out = append(out, byte('0'+chk))
+	}
+	return string(out), nil
+}
+
+func digitsString(digits []int) string {
+	out := make([]byte, len(digits))
+	for i, d := range digits {
+		out[i] = byte('0' + d)
+	}
+	return string(out)
+}
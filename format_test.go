@@ -0,0 +1,81 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bc   Barcode
+		want bool
+	}{
+		{
+			name: "valid EAN-13",
+			bc:   Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}},
+			want: true,
+		},
+		{
+			name: "invalid EAN-13 checksum",
+			bc:   Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 1}},
+			want: false,
+		},
+		{
+			name: "invalid EAN-13 length",
+			bc:   Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0}},
+			want: false,
+		},
+		{
+			name: "valid UPC-A",
+			bc:   Barcode{Symbology: UPCA, Digits: []int{0, 3, 6, 0, 0, 0, 2, 9, 1, 4, 5, 2}},
+			want: true,
+		},
+		{
+			name: "invalid UPC-A checksum",
+			bc:   Barcode{Symbology: UPCA, Digits: []int{0, 3, 6, 0, 0, 0, 2, 9, 1, 4, 5, 3}},
+			want: false,
+		},
+		{
+			name: "valid EAN-8",
+			bc:   Barcode{Symbology: EAN8, Digits: []int{9, 6, 3, 8, 5, 2, 1, 0}},
+			want: true,
+		},
+		{
+			name: "invalid EAN-8 checksum",
+			bc:   Barcode{Symbology: EAN8, Digits: []int{9, 6, 3, 8, 5, 2, 1, 1}},
+			want: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.bc.Validate()
+			if got := err == nil; got != tc.want {
+				t.Fatalf("invalid validation: got=%v (err=%v), want=%v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestISBN13(t *testing.T) {
+	bc := Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}}
+	if got, want := bc.ISBN13(), "978-0-13-419044-0"; got != want {
+		t.Fatalf("invalid ISBN-13:\ngot= %q\nwant=%q", got, want)
+	}
+}
+
+func TestISBN10(t *testing.T) {
+	bc := Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}}
+	got, err := bc.ISBN10()
+	if err != nil {
+		t.Fatalf("could not convert to ISBN-10: %+v", err)
+	}
+	if want := "0134190440"; got != want {
+		t.Fatalf("invalid ISBN-10:\ngot= %q\nwant=%q", got, want)
+	}
+
+	bc979 := Barcode{Symbology: EAN13, Digits: []int{9, 7, 9, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}}
+	if _, err := bc979.ISBN10(); err == nil {
+		t.Fatalf("expected error converting a 979-prefixed barcode to ISBN-10")
+	}
+}
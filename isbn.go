@@ -2,14 +2,15 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package isbn provides tools to read an ISBN barcode.
+// Package isbn provides tools to read EAN-13, UPC-A and EAN-8 barcodes and
+// to render EAN-13 ones, including the ISBN-13/ISBN-10 formatting specific
+// to book barcodes.
 package isbn
 
 import (
 	"bytes"
 	"fmt"
 	"image"
-	"image/color"
 	"math"
 )
 
@@ -18,88 +19,264 @@ const (
 	blackBar = 255
 )
 
-func Scan(src image.Image) (Barcode, error) {
-	dec := NewDecoder()
+func Scan(src image.Image, opts ...Option) (Barcode, error) {
+	dec := NewDecoder(opts...)
 	err := dec.Decode(src)
 	if err != nil {
-		return nil, fmt.Errorf("could not scan barcode image: %w", err)
+		return Barcode{}, fmt.Errorf("could not scan barcode image: %w", err)
 	}
 	return dec.Barcode, nil
 }
 
-// Barcode is an ISBN barcode.
-type Barcode []int
+// Barcode is a decoded (or to-be-encoded) barcode, tagged with the
+// symbology it was read as so callers can tell, e.g., a book ISBN from a
+// UPC-labelled product.
+type Barcode struct {
+	Symbology Symbology
+	Digits    []int
+	addOn     []int
+}
+
+// AddOn returns the digits of the EAN-5/EAN-2 supplemental add-on decoded
+// alongside bc, or nil if none was present. An EAN-5 add-on encodes a
+// price: e.g. 51999 decodes to US$19.99.
+func (bc Barcode) AddOn() []int {
+	return bc.addOn
+}
 
-// Decoder decodes an image containing an ISBN barcode.
+// Decoder decodes an image containing a barcode.
 type Decoder struct {
 	src image.Image
 
-	Y      int       // Y coordinate of line of sight.
+	Y      int       // Y coordinate of the winning line of sight; 0 when it was resampled along a located Region.
 	Line   []byte    // Line is the raw byte line of sight. (0=white,255=black)
 	Guards [3][3]Bar // Guards is the set of ISBN guards that have been detected
 
-	Barcode Barcode // Barcode is the decoded ISBN barcode.
+	Region [4]image.Point // Region is the detected barcode's bounding quadrilateral, if located; zero otherwise.
+	Angle  float64        // Angle is Region's rotation, in radians from horizontal, clockwise in image coordinates.
+
+	Barcode Barcode // Barcode is the decoded barcode.
+	AddOn   []int   // AddOn is the decoded EAN-5/EAN-2 supplemental add-on, if any.
+
+	ScanLines int           // ScanLines is the number of scanlines to try across the middle third of the image.
+	Threshold ThresholdFunc // Threshold computes the black/white binarization threshold; defaults to OtsuThreshold.
+
+	checkCksum bool   // checkCksum reports whether Decode validates the checksum of the decoded barcode.
+	digits     []int  // digits accumulates the digits decoded so far, across both groups of the symbol currently being digitized.
+	parity     []byte // parity records, per left-hand digit, which of codeL/codeG decoded it.
+}
+
+// Option configures a Decoder.
+type Option func(*Decoder)
+
+// WithChecksum enables (or disables) checksum validation of the decoded
+// barcode. When enabled, Decode rejects a barcode that fails its
+// symbology's checksum instead of silently returning it, so misreads can be caught by
+// the caller.
+func WithChecksum(validate bool) Option {
+	return func(dec *Decoder) {
+		dec.checkCksum = validate
+	}
+}
+
+// WithScanLines sets the number of scanlines Decode tries across the
+// middle third of the image before giving up.
+func WithScanLines(n int) Option {
+	return func(dec *Decoder) {
+		dec.ScanLines = n
+	}
+}
+
+// WithThreshold overrides the function used to binarize the image into
+// black/white pixels; it defaults to OtsuThreshold.
+func WithThreshold(fn ThresholdFunc) Option {
+	return func(dec *Decoder) {
+		dec.Threshold = fn
+	}
 }
 
-func NewDecoder() *Decoder {
-	return &Decoder{}
+// defaultScanLines is the number of scanlines tried by a Decoder created
+// without an explicit WithScanLines option.
+const defaultScanLines = 15
+
+func NewDecoder(opts ...Option) *Decoder {
+	dec := &Decoder{
+		ScanLines: defaultScanLines,
+		Threshold: OtsuThreshold,
+	}
+	for _, opt := range opts {
+		opt(dec)
+	}
+	return dec
 }
 
 func (dec *Decoder) Decode(src image.Image) error {
 	dec.src = src
-	dec.Y = src.Bounds().Max.Y / 2
 	err := dec.scan()
 	if err != nil {
 		return fmt.Errorf("could not decode barcode image: %w", err)
 	}
+	if dec.checkCksum {
+		err = dec.Barcode.Validate()
+		if err != nil {
+			return fmt.Errorf("could not decode barcode image: %w", err)
+		}
+	}
 	return nil
 }
 
-func (dec *Decoder) scan() error {
+// candidate is a tentative decode of one scanline, along with the evidence
+// backing it (see scan and locateAndScan).
+type candidate struct {
+	barcode Barcode
+	addOn   []int
+	guards  [3][3]Bar
+	line    []byte
+	y       int
+}
+
+// bestOf tries to decode each of lines, and returns the best-supported
+// result: a digit sequence with a passing checksum beats any without one,
+// and among those the one with the most agreeing lines wins. This makes decoding robust to a
+// single skewed, shadowed or noisy scanline.
+func (dec *Decoder) bestOf(lines [][]byte, ys []int) (candidate, int) {
 	var (
-		err error
-		b   = dec.src.Bounds()
-		mid = dec.Y
-		dst = image.NewGray(b)
-		scn = make([]byte, b.Max.X)
+		best  candidate
+		score = -1
+		votes = map[string]int{}
 	)
 
-	for y := 0; y < b.Max.Y; y++ {
-		for x := 0; x < b.Max.X; x++ {
-			pix := color.GrayModel.Convert(dec.src.At(x, y)).(color.Gray)
-			dst.Set(x, y, pix)
-			if y == mid {
-				switch {
-				case pix.Y > 128: // white
-					scn[x] = whiteBar
-				default:
-					scn[x] = blackBar
-				}
-				// log.Printf("img(x=%d,y=%d)= %v | %v", x, y, dst.At(x, y), scn[x])
-				dst.Set(x, y, color.Black)
+	for i, line := range lines {
+		bc, addOn, guards, err := dec.decodeLine(line)
+		if err != nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%d:%v", bc.Symbology, bc.Digits)
+		votes[key]++
+		s := votes[key]
+		if bc.Validate() == nil {
+			// a passing checksum is much stronger evidence of a correct
+			// decode than mere agreement across scanlines.
+			s += len(lines)
+		}
+		if s > score {
+			y := 0
+			if i < len(ys) {
+				y = ys[i]
+			}
+			score = s
+			best = candidate{barcode: bc, addOn: addOn, guards: guards, line: line, y: y}
+		}
+	}
+
+	return best, score
+}
+
+// scan tries to decode the barcode (and any supplemental add-on) on each
+// of several horizontal scanlines across the middle third of the image. If
+// none of them succeed, it falls back to locating the barcode wherever it
+// lies in the image, however rotated, and scanning lines parallel to its
+// long axis instead.
+func (dec *Decoder) scan() error {
+	if dec.Threshold == nil {
+		dec.Threshold = OtsuThreshold
+	}
+	thresh := dec.Threshold(dec.src)
+
+	ys := dec.scanLines(dec.src.Bounds())
+	lines := make([][]byte, len(ys))
+	for i, y := range ys {
+		lines[i] = scanLine(dec.src, y, thresh)
+	}
+
+	best, score := dec.bestOf(lines, ys)
+
+	if score < 0 {
+		if region, lines, ok := dec.locateLines(thresh); ok {
+			dec.Region = region
+			if c, s := dec.bestOf(lines, nil); s >= 0 {
+				best, score = c, s
 			}
 		}
 	}
 
-	dec.Line = scn
+	if score < 0 {
+		return fmt.Errorf("could not decode barcode on any of %d scanlines", len(lines))
+	}
+
+	dec.Barcode = best.barcode
+	dec.Barcode.addOn = best.addOn
+	dec.AddOn = best.addOn
+	dec.Guards = best.guards
+	dec.Line = best.line
+	dec.Y = best.y
+
+	return nil
+}
+
+// locateLines locates the barcode's region anywhere in dec.src and returns
+// a handful of thresholded scanlines resampled parallel to its long axis,
+// for the caller to try decoding in turn.
+func (dec *Decoder) locateLines(thresh uint8) (region [4]image.Point, lines [][]byte, ok bool) {
+	region, ok = locate(dec.src)
+	if !ok {
+		return region, nil, false
+	}
+
+	cx, cy, dirx, diry, perpx, perpy, length, width := rectAxis(region)
+	dec.Angle = math.Atan2(diry, dirx)
+
+	// the detected region is fit tightly around the barcode's own modules;
+	// pad it so a resampled line still has room for the quiet zone a
+	// trailing add-on (or its absence) is recognized by.
+	length += width
+
+	const nLines = 7
+	lines = make([][]byte, 0, nLines)
+	for i := 0; i < nLines; i++ {
+		offset := width * (float64(i)/float64(nLines-1) - 0.5)
+		lines = append(lines, resampleLine(dec.src, cx, cy, dirx, diry, perpx, perpy, offset, int(length), thresh))
+	}
+	return region, lines, true
+}
+
+// decodeLine tries to locate and digitize a barcode (and any supplemental
+// add-on) on a single thresholded scanline. It tries each of lineSpecs in
+// turn and returns the first one whose bars digitize cleanly.
+func (dec *Decoder) decodeLine(scn []byte) (bc Barcode, addOn []int, guards [3][3]Bar, err error) {
+	dec.digits = nil
+	dec.parity = nil
+
+	defer func() {
+		// most candidate scanlines don't cross the barcode at all, which
+		// can drive the bar-counting logic below out of bounds; treat
+		// that as just another failed line rather than aborting the scan.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not decode scanline: %v", r)
+		}
+	}()
 
 	var (
 		black = []byte{blackBar}
 		white = []byte{whiteBar}
 	)
 
-	// ISBNs (EAN-13) consist of a left guard (3 bars: black,white,black),
-	// a middle guard (b,w,b) and a right guard (b,w,b).
-	// [bwb] [6 pairs (w,b)] [w] [bwb] [6 pairs (w,b)] [w] [bwb]
-	//
-	// we first try to find the left guard.
+	// every symbology we support starts with a left guard (3 bars:
+	// black,white,black), ends with a right guard (b,w,b), and has a middle
+	// guard (w,b,w,b,w) between its two digit groups; only the digit count
+	// on either side of the middle guard varies. we first try to find the
+	// left guard.
 	var (
-		gl1 = &dec.Guards[0][0] // black
-		gl2 = &dec.Guards[0][1] // white
-		gl3 = &dec.Guards[0][2] // black
+		gl1 = &guards[0][0] // black
+		gl2 = &guards[0][1] // white
+		gl3 = &guards[0][2] // black
 	)
 
 	gl1.Beg = bytes.Index(scn, black)
+	if gl1.Beg < 0 {
+		return bc, nil, guards, fmt.Errorf("could not find left guard")
+	}
 	gl1.End = bytes.Index(scn[gl1.Beg:], white) + gl1.Beg
 
 	gl2.Beg = bytes.Index(scn[gl1.End:], white) + gl1.End
@@ -108,32 +285,52 @@ func (dec *Decoder) scan() error {
 	gl3.Beg = bytes.Index(scn[gl2.End:], black) + gl2.End
 	gl3.End = bytes.Index(scn[gl3.Beg:], white) + gl3.Beg
 
-	// now try to find the right guard.
+	// now find the right guard by counting bars from the left guard, rather
+	// than searching backwards from the end of the line: a supplemental
+	// EAN-5/EAN-2 add-on may follow the main symbol, so the last black
+	// pixel on the line need not belong to the right guard.
+	all := dec.linearize(scn[gl1.Beg:])
+
 	var (
-		gr1 = &dec.Guards[2][0]
-		gr2 = &dec.Guards[2][1]
-		gr3 = &dec.Guards[2][2]
+		gr1 = &guards[2][0]
+		gr2 = &guards[2][1]
+		gr3 = &guards[2][2]
 	)
-	gr3.End = bytes.LastIndex(scn, black) - 1
-	gr3.Beg = bytes.LastIndex(scn[:gr3.End], white)
 
-	gr2.End = bytes.LastIndex(scn[:gr3.Beg], white) - 1
-	gr2.Beg = bytes.LastIndex(scn[:gr2.End], black)
+	var lastErr error
+	for _, sp := range lineSpecs {
+		n := sp.mainBarsLen()
+		if len(all) < n {
+			lastErr = fmt.Errorf("only %d bars decoded, want=%d", len(all), n)
+			continue
+		}
+		bars := all[:n]
+
+		*gr1 = bars[n-3].offset(gl1.Beg)
+		*gr2 = bars[n-2].offset(gl1.Beg)
+		*gr3 = bars[n-1].offset(gl1.Beg)
 
-	gr1.End = bytes.LastIndex(scn[:gr2.Beg], black) - 1
-	gr1.Beg = bytes.LastIndex(scn[:gr1.End], white)
+		bc, err = dec.digitize(bars, sp)
+		if err != nil {
+			lastErr = fmt.Errorf("could not digitize bars pattern: %w", err)
+			continue
+		}
+		bc = classifyEAN13(bc)
 
-	bars := dec.linearize(scn[gl1.Beg:gr3.End])
+		mod := (float64(gl1.len()) + float64(gl2.len()) + float64(gl3.len())) / 3
+		addOn = dec.scanAddOn(scn[gr3.End:], mod)
 
-	dec.Barcode, err = dec.digitize(bars)
-	if err != nil {
-		return fmt.Errorf("could not digitize bars pattern: %w", err)
+		return bc, addOn, guards, nil
 	}
 
-	return nil
+	return Barcode{}, nil, guards, lastErr
 }
 
 func (dec *Decoder) linearize(raw []byte) []Bar {
+	if len(raw) == 0 {
+		return nil
+	}
+
 	var (
 		bars []Bar
 		cur  *Bar
@@ -168,12 +365,18 @@ func (dec *Decoder) linearize(raw []byte) []Bar {
 	return bars
 }
 
-func (dec *Decoder) digitize(bars []Bar) (Barcode, error) {
+// digitize decodes bars (a slice sized to sp.mainBarsLen()) into a Barcode,
+// following sp's guard layout: a left guard, sp.leftDigits digits, a middle
+// guard, sp.rightDigits digits, and a right guard.
+func (dec *Decoder) digitize(bars []Bar, sp symbologySpec) (Barcode, error) {
 	const (
 		white = 0
 		black = 1
 	)
 
+	dec.digits = nil
+	dec.parity = nil
+
 	var (
 		err error
 		beg = 0
@@ -182,95 +385,85 @@ func (dec *Decoder) digitize(bars []Bar) (Barcode, error) {
 
 	err = validate(bars[beg:end+1], []int{black, white, black, white})
 	if err != nil {
-		return nil, err
-	}
-
-	{
-		var (
-			b1 = bars[0]
-			b2 = bars[1]
-			b3 = bars[2]
-		)
-		mod := float64(b1.len()+b2.len()+b3.len()) / 3
-		sli := bars[end : end+24]
-		for i := 0; i < len(sli); i += 4 {
-			sub := sli[i : i+4]
-			tot := 0.0
-			for _, bar := range sub {
-				tot += float64(bar.len())
-			}
-			tot /= mod
-			tot = math.Round(tot)
-			if tot != 7 {
-				return nil, fmt.Errorf(
-					"could not decode first sequence: invalid digitization: got=%v, want=%v",
-					tot, 7,
-				)
-			}
-			out := make([]byte, 0, 7)
-			for _, bar := range sub {
-				n := int(math.Round(float64(bar.len()) / mod))
-				for j := 0; j < n; j++ {
-					out = append(out, byte(bar.Col))
-				}
-			}
-			_, err := dec.decodeA(out)
-			if err != nil {
-				return nil, fmt.Errorf("could not decode first sequence: %w", err)
-			}
-			// log.Printf("bar[%d]: %v -> %d", i/4+1, out, v)
+		return Barcode{}, err
+	}
+
+	leftDecode := dec.decodeA
+	if !sp.parity {
+		leftDecode = dec.decodeL
+	}
+	mod := groupMod(bars[0], bars[1], bars[2])
+	if err := dec.digitizeGroup(bars[end:end+sp.leftDigits*4], mod, leftDecode, "first"); err != nil {
+		return Barcode{}, err
+	}
+
+	digits := dec.digits
+	if sp.parity {
+		// the left-hand group never encodes the first digit directly: it is
+		// only implied by the L/G parity pattern of its digits. Recover it
+		// now and prepend it to the digits decoded so far.
+		first, ok := parityToDigit[string(dec.parity)]
+		if !ok {
+			return Barcode{}, fmt.Errorf("could not determine first digit: invalid parity pattern %q", dec.parity)
 		}
+		digits = append([]int{first}, digits...)
 	}
 
-	beg = end + 24
+	beg = end + sp.leftDigits*4
 	end = beg + 5
 	err = validate(bars[beg:end], []int{white, black, white, black, white})
 	if err != nil {
-		return nil, err
-	}
-	{
-		var (
-			b1 = bars[beg+1]
-			b2 = bars[beg+2]
-			b3 = bars[beg+3]
-		)
-		mod := float64(b1.len()+b2.len()+b3.len()) / 3
-		sli := bars[end : end+24]
-		for i := 0; i < len(sli); i += 4 {
-			sub := sli[i : i+4]
-			tot := 0.0
-			for _, bar := range sub {
-				tot += float64(bar.len())
-			}
-			tot /= mod
-			tot = math.Round(tot)
-			if tot != 7 {
-				return nil, fmt.Errorf(
-					"could not decode second sequence: invalid digitization: got=%v, want=%v",
-					tot, 7,
-				)
-			}
-			out := make([]byte, 0, 7)
-			for _, bar := range sub {
-				n := int(math.Round(float64(bar.len()) / mod))
-				for j := 0; j < n; j++ {
-					out = append(out, byte(bar.Col))
-				}
-			}
-			_, err := dec.decodeB(out)
-			if err != nil {
-				return nil, fmt.Errorf("could not decode second sequence: %w", err)
-			}
-			// log.Printf("bar[%d]: %v -> %d", i/4+1, out, v)
-		}
+		return Barcode{}, err
 	}
 
+	mod = groupMod(bars[beg+1], bars[beg+2], bars[beg+3])
+	if err := dec.digitizeGroup(bars[end:end+sp.rightDigits*4], mod, dec.decodeB, "second"); err != nil {
+		return Barcode{}, err
+	}
+	digits = append(digits, dec.digits[len(dec.digits)-sp.rightDigits:]...)
+
 	err = validate(bars[len(bars)-4:], []int{white, black, white, black})
 	if err != nil {
-		return nil, err
+		return Barcode{}, err
 	}
 
-	return Barcode(dec.Barcode), nil
+	return Barcode{Symbology: sp.sym, Digits: digits}, nil
+}
+
+// groupMod returns the reference module width for a digit group, taken as
+// the average length of the three guard bars straddling it.
+func groupMod(b1, b2, b3 Bar) float64 {
+	return float64(b1.len()+b2.len()+b3.len()) / 3
+}
+
+// digitizeGroup decodes len(sli)/4 digits (4 bars, 7 modules each) from
+// sli, decoding each one with decode and appending it to dec.digits.
+func (dec *Decoder) digitizeGroup(sli []Bar, mod float64, decode func([]byte) (int, error), label string) error {
+	for i := 0; i < len(sli); i += 4 {
+		sub := sli[i : i+4]
+		tot := 0.0
+		for _, bar := range sub {
+			tot += float64(bar.len())
+		}
+		tot = math.Round(tot / mod)
+		if tot != 7 {
+			return fmt.Errorf(
+				"could not decode %s sequence: invalid digitization: got=%v, want=%v",
+				label, tot, 7,
+			)
+		}
+		out := make([]byte, 0, 7)
+		for _, bar := range sub {
+			n := int(math.Round(float64(bar.len()) / mod))
+			for j := 0; j < n; j++ {
+				out = append(out, byte(bar.Col))
+			}
+		}
+		if _, err := decode(out); err != nil {
+			return fmt.Errorf("could not decode %s sequence: %w", label, err)
+		}
+	}
+	return nil
 }
 
 var codeL = map[string]int{
@@ -312,28 +505,43 @@ var codeR = map[string]int{
 	string([]byte{1, 1, 1, 0, 1, 0, 0}): 9,
 }
 
-var codecs = []map[string]int{
-	codeL, codeG, codeG, codeL, codeG, codeL,
+// decodeA decodes one of the six left-hand digits of an EAN-13 barcode,
+// which may use either the L or the G code table; it records which one was
+// used in dec.parity, so the symbol's implied leading digit can be
+// recovered once every left-hand digit has been read.
+func (dec *Decoder) decodeA(v []byte) (int, error) {
+	if o, ok := codeL[string(v)]; ok {
+		dec.parity = append(dec.parity, 'L')
+		dec.digits = append(dec.digits, o)
+		return o, nil
+	}
+	if o, ok := codeG[string(v)]; ok {
+		dec.parity = append(dec.parity, 'G')
+		dec.digits = append(dec.digits, o)
+		return o, nil
+	}
+	return 0, fmt.Errorf("invalid codec/value %q", v)
 }
 
-func (dec *Decoder) decodeA(v []byte) (int, error) {
-	var (
-		i     = len(dec.Barcode)
-		o, ok = codecs[i][string(v)]
-	)
+// decodeL decodes a left-hand digit of a symbology with no L/G parity
+// (EAN-8), which always uses the L code table.
+func (dec *Decoder) decodeL(v []byte) (int, error) {
+	o, ok := codeL[string(v)]
 	if !ok {
 		return 0, fmt.Errorf("invalid codec/value %q", v)
 	}
-	dec.Barcode = append(dec.Barcode, o)
+	dec.digits = append(dec.digits, o)
 	return o, nil
 }
 
+// decodeB decodes one of a symbol's right-hand digits, which always use the
+// R code table.
 func (dec *Decoder) decodeB(v []byte) (int, error) {
 	o, ok := codeR[string(v)]
 	if !ok {
 		return 0, fmt.Errorf("invalid codec/value %q", v)
 	}
-	dec.Barcode = append(dec.Barcode, o)
+	dec.digits = append(dec.digits, o)
 	return o, nil
 }
 
@@ -374,3 +582,9 @@ func newBar(raw []byte, off int, col int) Bar {
 }
 
 func (b Bar) len() int { return b.End - b.Beg }
+
+// offset translates a Bar computed relative to a sub-slice of the scanline
+// back into absolute scanline coordinates.
+func (b Bar) offset(o int) Bar {
+	return Bar{Beg: b.Beg + o, End: b.End + o, Col: b.Col}
+}
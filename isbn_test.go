@@ -18,11 +18,11 @@ func TestScan(t *testing.T) {
 	}{
 		//		{
 		//			name: "testdata/test-apue.png",
-		//			want: Barcode{9, 7, 8, 0, 2, 0, 1, 4, 3, 3, 0, 7, 4},
+		//			want: Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 2, 0, 1, 4, 3, 3, 0, 7, 4}},
 		//		},
 		{
 			name: "testdata/test-gopl.png",
-			want: Barcode{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0},
+			want: Barcode{Symbology: EAN13, Digits: []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}},
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
@@ -48,3 +48,77 @@ func TestScan(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeLineEAN8(t *testing.T) {
+	// 96385210 (EAN-8, valid checksum): left group decoded with codeL only
+	// (EAN-8 has no L/G parity), right group with codeR.
+	raw := buildScanline([]int{9, 6, 3, 8}, "", []int{5, 2, 1, 0}, 2)
+
+	dec := NewDecoder()
+	bc, _, _, err := dec.decodeLine(raw)
+	if err != nil {
+		t.Fatalf("could not decode EAN-8 scanline: %+v", err)
+	}
+
+	want := Barcode{Symbology: EAN8, Digits: []int{9, 6, 3, 8, 5, 2, 1, 0}}
+	if !reflect.DeepEqual(bc, want) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", bc, want)
+	}
+}
+
+func TestDecodeLineUPCA(t *testing.T) {
+	// 036000291452 (UPC-A, valid checksum): its bars are EAN-13's, with an
+	// implicit leading 0 (parity "LLLLLL"); classifyEAN13 recognizes that
+	// and drops it from the reported digits.
+	raw := buildScanline([]int{0, 3, 6, 0, 0, 0}, "LLLLLL", []int{2, 9, 1, 4, 5, 2}, 2)
+
+	dec := NewDecoder()
+	bc, _, _, err := dec.decodeLine(raw)
+	if err != nil {
+		t.Fatalf("could not decode UPC-A scanline: %+v", err)
+	}
+
+	want := Barcode{Symbology: UPCA, Digits: []int{0, 3, 6, 0, 0, 0, 2, 9, 1, 4, 5, 2}}
+	if !reflect.DeepEqual(bc, want) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", bc, want)
+	}
+}
+
+// buildScanline renders leftDigits and rightDigits as a raw
+// (quiet-zone-prefixed) scanline: a left guard, leftDigits (coded per
+// parity, defaulting to all-L when parity is empty), a middle guard, and
+// rightDigits coded with codeR, for use by tests that exercise decodeLine
+// directly rather than going through Encode (which only targets EAN-13).
+func buildScanline(leftDigits []int, parity string, rightDigits []int, mod int) []byte {
+	modules := []byte{1, 0, 1} // left guard: b,w,b
+	for i, d := range leftDigits {
+		tbl := codeLByDigit
+		if parity != "" && parity[i] == 'G' {
+			tbl = codeGByDigit
+		}
+		modules = append(modules, tbl[d]...)
+	}
+	modules = append(modules, 0, 1, 0, 1, 0) // middle guard: w,b,w,b,w
+	for _, d := range rightDigits {
+		modules = append(modules, codeRByDigit[d]...)
+	}
+	modules = append(modules, 1, 0, 1) // right guard: b,w,b
+
+	raw := make([]byte, 0, 40+len(modules)*mod)
+	for i := 0; i < 20; i++ {
+		raw = append(raw, whiteBar)
+	}
+	for _, m := range modules {
+		v := byte(whiteBar)
+		if m == 1 {
+			v = blackBar
+		}
+		for k := 0; k < mod; k++ {
+			raw = append(raw, v)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		raw = append(raw, whiteBar)
+	}
+	return raw
+}
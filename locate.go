@@ -0,0 +1,460 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// locate looks for a barcode-shaped region anywhere in src, instead of
+// assuming one lies horizontally across the middle of the image. It finds
+// the region by computing the horizontal gradient magnitude (barcodes are
+// made of many vertical bar edges, so they stand out as a dense patch of
+// gradient even when rotated), thresholding and morphologically closing it
+// into a solid blob, then picking the connected component that looks most
+// like a barcode and fitting it with a minimum-area rectangle.
+//
+// It returns that rectangle's four corners (in src's coordinates) and
+// reports whether a plausible region was found at all.
+func locate(src image.Image) (corners [4]image.Point, ok bool) {
+	gray := toGraySlice(src)
+	if len(gray) == 0 || len(gray[0]) == 0 {
+		return corners, false
+	}
+
+	grad := gradientMagnitude(gray)
+	mask := thresholdMask(grad, otsu(histogramOf(grad)))
+
+	const (
+		closeKW = 15 // wide enough to bridge the gaps between a barcode's bars
+		closeKH = 3  // tall enough to tolerate a few degrees of rotation
+	)
+	mask = erode(dilate(mask, closeKW, closeKH), closeKW, closeKH)
+
+	comp, found := selectBarcodeComponent(connectedComponents(mask))
+	if !found {
+		return corners, false
+	}
+
+	corners = minAreaRect(convexHull(comp.points))
+
+	b := src.Bounds()
+	for i := range corners {
+		corners[i].X += b.Min.X
+		corners[i].Y += b.Min.Y
+	}
+	return corners, true
+}
+
+// toGraySlice converts src to a dense [y][x] grayscale grid, in coordinates
+// relative to src.Bounds().Min.
+func toGraySlice(src image.Image) [][]uint8 {
+	b := src.Bounds()
+	out := make([][]uint8, b.Dy())
+	for y := range out {
+		row := make([]uint8, b.Dx())
+		for x := range row {
+			row[x] = grayAt(src, b.Min.X+x, b.Min.Y+y)
+		}
+		out[y] = row
+	}
+	return out
+}
+
+// gradientMagnitude computes the absolute horizontal gradient of gray: the
+// bars of a barcode, wherever it is oriented, are made of long straight
+// edges with a strong gradient across them.
+func gradientMagnitude(gray [][]uint8) [][]uint8 {
+	out := make([][]uint8, len(gray))
+	for y, row := range gray {
+		w := len(row)
+		grad := make([]uint8, w)
+		for x := range row {
+			x0, x1 := x-1, x+1
+			if x0 < 0 {
+				x0 = 0
+			}
+			if x1 >= w {
+				x1 = w - 1
+			}
+			d := int(row[x1]) - int(row[x0])
+			if d < 0 {
+				d = -d
+			}
+			if d > 255 {
+				d = 255
+			}
+			grad[x] = uint8(d)
+		}
+		out[y] = grad
+	}
+	return out
+}
+
+// histogramOf returns the grayscale histogram of gray, for use with otsu.
+func histogramOf(gray [][]uint8) [256]int {
+	var hist [256]int
+	for _, row := range gray {
+		for _, v := range row {
+			hist[v]++
+		}
+	}
+	return hist
+}
+
+// thresholdMask reports, for each pixel of gray, whether it is above t.
+func thresholdMask(gray [][]uint8, t uint8) [][]bool {
+	mask := make([][]bool, len(gray))
+	for y, row := range gray {
+		m := make([]bool, len(row))
+		for x, v := range row {
+			m[x] = v > t
+		}
+		mask[y] = m
+	}
+	return mask
+}
+
+// dilate grows every set pixel of mask into its kw x kh neighborhood.
+func dilate(mask [][]bool, kw, kh int) [][]bool {
+	h := len(mask)
+	if h == 0 {
+		return mask
+	}
+	w := len(mask[0])
+	out := newMask(w, h)
+
+	hw, hh := kw/2, kh/2
+	for y, row := range mask {
+		for x, v := range row {
+			if !v {
+				continue
+			}
+			for dy := -hh; dy <= hh; dy++ {
+				ny := y + dy
+				if ny < 0 || ny >= h {
+					continue
+				}
+				for dx := -hw; dx <= hw; dx++ {
+					nx := x + dx
+					if nx < 0 || nx >= w {
+						continue
+					}
+					out[ny][nx] = true
+				}
+			}
+		}
+	}
+	return out
+}
+
+// erode shrinks mask, keeping only pixels whose full kw x kh neighborhood is
+// set.
+func erode(mask [][]bool, kw, kh int) [][]bool {
+	h := len(mask)
+	if h == 0 {
+		return mask
+	}
+	w := len(mask[0])
+	out := newMask(w, h)
+
+	hw, hh := kw/2, kh/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			set := true
+		neighborhood:
+			for dy := -hh; dy <= hh; dy++ {
+				ny := y + dy
+				if ny < 0 || ny >= h {
+					set = false
+					break
+				}
+				for dx := -hw; dx <= hw; dx++ {
+					nx := x + dx
+					if nx < 0 || nx >= w || !mask[ny][nx] {
+						set = false
+						break neighborhood
+					}
+				}
+			}
+			out[y][x] = set
+		}
+	}
+	return out
+}
+
+func newMask(w, h int) [][]bool {
+	out := make([][]bool, h)
+	for y := range out {
+		out[y] = make([]bool, w)
+	}
+	return out
+}
+
+// component is a connected component of a binary mask.
+type component struct {
+	points []image.Point
+	bounds image.Rectangle
+}
+
+// connectedComponents labels the 8-connected components of mask.
+func connectedComponents(mask [][]bool) []component {
+	h := len(mask)
+	if h == 0 {
+		return nil
+	}
+	w := len(mask[0])
+	visited := newMask(w, h)
+
+	var dirs = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+	var comps []component
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !mask[y][x] || visited[y][x] {
+				continue
+			}
+
+			comp := component{bounds: image.Rect(x, y, x+1, y+1)}
+			queue := []image.Point{{X: x, Y: y}}
+			visited[y][x] = true
+			for len(queue) > 0 {
+				p := queue[len(queue)-1]
+				queue = queue[:len(queue)-1]
+				comp.points = append(comp.points, p)
+				comp.bounds = comp.bounds.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+
+				for _, d := range dirs {
+					nx, ny := p.X+d[0], p.Y+d[1]
+					if nx < 0 || nx >= w || ny < 0 || ny >= h || visited[ny][nx] || !mask[ny][nx] {
+						continue
+					}
+					visited[ny][nx] = true
+					queue = append(queue, image.Point{X: nx, Y: ny})
+				}
+			}
+			comps = append(comps, comp)
+		}
+	}
+	return comps
+}
+
+// selectBarcodeComponent picks the component of comps most likely to be a
+// barcode: noticeably wider than it is tall, and densely filled rather than
+// a sparse, irregular blob.
+func selectBarcodeComponent(comps []component) (component, bool) {
+	const (
+		minAspect  = 1.5
+		maxAspect  = 20
+		minDensity = 0.2
+	)
+
+	var (
+		best      component
+		bestScore float64
+		found     bool
+	)
+	for _, c := range comps {
+		w, h := float64(c.bounds.Dx()), float64(c.bounds.Dy())
+		if h == 0 {
+			continue
+		}
+		aspect := w / h
+		if aspect < minAspect || aspect > maxAspect {
+			continue
+		}
+		density := float64(len(c.points)) / (w * h)
+		if density < minDensity {
+			continue
+		}
+		if score := aspect * density; !found || score > bestScore {
+			best, bestScore, found = c, score, true
+		}
+	}
+	return best, found
+}
+
+// convexHull returns the convex hull of pts, in counter-clockwise order,
+// using Andrew's monotone chain algorithm.
+func convexHull(pts []image.Point) []image.Point {
+	if len(pts) < 3 {
+		return pts
+	}
+
+	sorted := append([]image.Point{}, pts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+
+	cross := func(o, a, b image.Point) int {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+
+	n := len(sorted)
+	hull := make([]image.Point, 0, 2*n)
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := n - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// minAreaRect fits hull (a convex polygon) with its minimum-area bounding
+// rectangle, using the rotating calipers technique: the minimum-area
+// rectangle enclosing a convex polygon always has one side flush with one
+// of the polygon's edges, so it suffices to try each edge's orientation in
+// turn. It returns the four corners of the winning rectangle, in order.
+func minAreaRect(hull []image.Point) (corners [4]image.Point) {
+	switch len(hull) {
+	case 0:
+		return corners
+	case 1, 2:
+		for i := range corners {
+			corners[i] = hull[i%len(hull)]
+		}
+		return corners
+	}
+
+	bestArea := math.Inf(1)
+	for i := range hull {
+		p1, p2 := hull[i], hull[(i+1)%len(hull)]
+		dx, dy := float64(p2.X-p1.X), float64(p2.Y-p1.Y)
+		edgeLen := math.Hypot(dx, dy)
+		if edgeLen == 0 {
+			continue
+		}
+		ux, uy := dx/edgeLen, dy/edgeLen // unit vector along the edge
+		vx, vy := -uy, ux                // unit vector perpendicular to it
+
+		minU, maxU := math.Inf(1), math.Inf(-1)
+		minV, maxV := math.Inf(1), math.Inf(-1)
+		for _, p := range hull {
+			px, py := float64(p.X), float64(p.Y)
+			u := px*ux + py*uy
+			v := px*vx + py*vy
+			minU, maxU = math.Min(minU, u), math.Max(maxU, u)
+			minV, maxV = math.Min(minV, v), math.Max(maxV, v)
+		}
+
+		if area := (maxU - minU) * (maxV - minV); area < bestArea {
+			bestArea = area
+			corner := func(u, v float64) image.Point {
+				return image.Point{
+					X: int(math.Round(u*ux + v*vx)),
+					Y: int(math.Round(u*uy + v*vy)),
+				}
+			}
+			corners = [4]image.Point{
+				corner(minU, minV),
+				corner(maxU, minV),
+				corner(maxU, maxV),
+				corner(minU, maxV),
+			}
+		}
+	}
+	return corners
+}
+
+// rectAxis decodes corners (as returned by locate) into the rectangle's
+// center, its unit direction vector along the long axis, the perpendicular
+// unit vector, and its length and width.
+func rectAxis(corners [4]image.Point) (cx, cy, dirx, diry, perpx, perpy, length, width float64) {
+	for _, c := range corners {
+		cx += float64(c.X)
+		cy += float64(c.Y)
+	}
+	cx /= 4
+	cy /= 4
+
+	e01x, e01y := float64(corners[1].X-corners[0].X), float64(corners[1].Y-corners[0].Y)
+	e12x, e12y := float64(corners[2].X-corners[1].X), float64(corners[2].Y-corners[1].Y)
+	len01, len12 := math.Hypot(e01x, e01y), math.Hypot(e12x, e12y)
+
+	if len01 >= len12 {
+		length, width = len01, len12
+		dirx, diry = e01x/len01, e01y/len01
+	} else {
+		length, width = len12, len01
+		dirx, diry = e12x/len12, e12y/len12
+	}
+	perpx, perpy = -diry, dirx
+	return cx, cy, dirx, diry, perpx, perpy, length, width
+}
+
+// bilinearAt samples src's grayscale value at the (possibly fractional)
+// coordinates (x,y) by bilinear interpolation, clamping to src's bounds.
+func bilinearAt(src image.Image, x, y float64) uint8 {
+	b := src.Bounds()
+	clampX := func(v int) int {
+		switch {
+		case v < b.Min.X:
+			return b.Min.X
+		case v >= b.Max.X:
+			return b.Max.X - 1
+		default:
+			return v
+		}
+	}
+	clampY := func(v int) int {
+		switch {
+		case v < b.Min.Y:
+			return b.Min.Y
+		case v >= b.Max.Y:
+			return b.Max.Y - 1
+		default:
+			return v
+		}
+	}
+
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	x1, y1 := x0+1, y0+1
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	g00 := float64(grayAt(src, clampX(x0), clampY(y0)))
+	g10 := float64(grayAt(src, clampX(x1), clampY(y0)))
+	g01 := float64(grayAt(src, clampX(x0), clampY(y1)))
+	g11 := float64(grayAt(src, clampX(x1), clampY(y1)))
+
+	top := g00*(1-fx) + g10*fx
+	bot := g01*(1-fx) + g11*fx
+	return uint8(math.Round(top*(1-fy) + bot*fy))
+}
+
+// resampleLine bilinearly samples src along a line of length segments
+// centered on (cx,cy), running in direction (dirx,diry) and offset from
+// the center by offset along the perpendicular (perpx,perpy), producing a
+// raw (0=white,255=black) scanline thresholded at thresh. This is how a
+// rotated barcode is turned into the same kind of scanline decodeLine
+// already knows how to digitize.
+func resampleLine(src image.Image, cx, cy, dirx, diry, perpx, perpy, offset float64, length int, thresh uint8) []byte {
+	out := make([]byte, length)
+	half := float64(length) / 2
+	for i := range out {
+		t := float64(i) - half
+		x := cx + dirx*t + perpx*offset
+		y := cy + diry*t + perpy*offset
+		if bilinearAt(src, x, y) > thresh {
+			out[i] = whiteBar
+		} else {
+			out[i] = blackBar
+		}
+	}
+	return out
+}
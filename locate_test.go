@@ -0,0 +1,111 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestConvexHull(t *testing.T) {
+	// a 10x4 rectangle, plus an interior point that must not end up on the
+	// hull.
+	pts := []image.Point{
+		{0, 0}, {9, 0}, {9, 3}, {0, 3}, {5, 2},
+	}
+	hull := convexHull(pts)
+	if len(hull) != 4 {
+		t.Fatalf("invalid hull size: got=%d, want=4 (hull=%v)", len(hull), hull)
+	}
+	for _, p := range hull {
+		if p == (image.Point{5, 2}) {
+			t.Fatalf("interior point %v incorrectly kept on hull: %v", p, hull)
+		}
+	}
+}
+
+func TestMinAreaRect(t *testing.T) {
+	// an axis-aligned rectangle: its minimum-area rectangle is itself.
+	hull := convexHull([]image.Point{{0, 0}, {20, 0}, {20, 5}, {0, 5}})
+	corners := minAreaRect(hull)
+
+	_, _, dirx, diry, _, _, length, width := rectAxis(corners)
+	if got, want := math.Round(length), 20.0; got != want {
+		t.Fatalf("invalid length: got=%v, want=%v", got, want)
+	}
+	if got, want := math.Round(width), 5.0; got != want {
+		t.Fatalf("invalid width: got=%v, want=%v", got, want)
+	}
+	if angle := math.Atan2(diry, dirx); math.Abs(angle) > 1e-9 {
+		t.Fatalf("invalid angle: got=%v, want=0", angle)
+	}
+}
+
+func TestBilinearAt(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.SetGray(0, 0, color.Gray{Y: 0})
+	img.SetGray(1, 0, color.Gray{Y: 100})
+	img.SetGray(0, 1, color.Gray{Y: 100})
+	img.SetGray(1, 1, color.Gray{Y: 200})
+
+	if got, want := bilinearAt(img, 0.5, 0.5), uint8(100); got != want {
+		t.Fatalf("invalid bilinear sample: got=%d, want=%d", got, want)
+	}
+}
+
+func TestLocateRotatedBarcode(t *testing.T) {
+	digits := []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}
+	bc, err := Encode(digits, EncodeOptions{ModuleWidth: 3, Height: 40})
+	if err != nil {
+		t.Fatalf("could not encode barcode: %+v", err)
+	}
+
+	const angle = 7 * math.Pi / 180
+	img := rotate(bc, angle, 400, 400)
+
+	dec := NewDecoder()
+	if err := dec.Decode(img); err != nil {
+		t.Fatalf("could not decode rotated barcode: %+v", err)
+	}
+	if got := dec.Barcode.Digits; !reflect.DeepEqual(got, digits) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", got, digits)
+	}
+	if dec.Region == ([4]image.Point{}) {
+		t.Fatalf("expected a located Region for a rotated barcode")
+	}
+}
+
+// rotate draws src, rotated by angle radians and centered within a w x h
+// white canvas, by bilinear-sampling src at each destination pixel's
+// pre-image under the rotation. It is the inverse of the resampling done
+// by resampleLine, so it doubles as a way to manufacture skewed test
+// images without any platform image-processing dependency.
+func rotate(src image.Image, angle float64, w, h int) image.Image {
+	sb := src.Bounds()
+	scx, scy := float64(sb.Min.X+sb.Max.X)/2, float64(sb.Min.Y+sb.Max.Y)/2
+	dcx, dcy := float64(w)/2, float64(h)/2
+
+	cos, sin := math.Cos(-angle), math.Sin(-angle)
+
+	dst := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-dcx, float64(y)-dcy
+			sx := dx*cos - dy*sin + scx
+			sy := dx*sin + dy*cos + scy
+			if sx < float64(sb.Min.X) || sx >= float64(sb.Max.X) || sy < float64(sb.Min.Y) || sy >= float64(sb.Max.Y) {
+				continue
+			}
+			dst.SetGray(x, y, color.Gray{Y: bilinearAt(src, sx, sy)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+// digitToParity maps an EAN-13 first digit to the L/G parity pattern used
+// to encode the barcode's six left-hand digits; that first digit is never
+// encoded directly, only implied by this pattern.
+var digitToParity = map[int]string{
+	0: "LLLLLL",
+	1: "LLGLGG",
+	2: "LLGGLG",
+	3: "LLGGGL",
+	4: "LGLLGG",
+	5: "LGGLLG",
+	6: "LGGGLL",
+	7: "LGLGLG",
+	8: "LGLGGL",
+	9: "LGGLGL",
+}
+
+// parityToDigit is the inverse of digitToParity.
+var parityToDigit = func() map[string]int {
+	m := make(map[string]int, len(digitToParity))
+	for d, p := range digitToParity {
+		m[p] = d
+	}
+	return m
+}()
+
+// codeLByDigit, codeGByDigit and codeRByDigit are the inverses of
+// codeL, codeG and codeR: the 7-module pattern used to encode a digit.
+var (
+	codeLByDigit = reverse(codeL)
+	codeGByDigit = reverse(codeG)
+	codeRByDigit = reverse(codeR)
+)
+
+func reverse(tbl map[string]int) [10][]byte {
+	var out [10][]byte
+	for k, v := range tbl {
+		out[v] = []byte(k)
+	}
+	return out
+}
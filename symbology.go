@@ -0,0 +1,96 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import "fmt"
+
+// Symbology identifies which barcode family a Barcode was decoded from (or
+// should be encoded as).
+type Symbology int
+
+const (
+	EAN13 Symbology = iota // EAN-13, the symbology used by book ISBNs.
+	UPCA                   // UPC-A, the symbology used by most US/Canada retail products.
+	EAN8                   // EAN-8, a compact symbology for small packaging.
+)
+
+func (s Symbology) String() string {
+	switch s {
+	case EAN13:
+		return "EAN-13"
+	case UPCA:
+		return "UPC-A"
+	case EAN8:
+		return "EAN-8"
+	default:
+		return fmt.Sprintf("Symbology(%d)", int(s))
+	}
+}
+
+// symbologySpec describes one symbology's bar layout and check-digit rule,
+// so the decoder can share its logic across all of them instead of
+// hard-coding EAN-13's. The encoder only produces EAN-13.
+type symbologySpec struct {
+	sym Symbology
+
+	leftDigits  int // number of digits in the left-hand group
+	rightDigits int // number of digits in the right-hand group
+
+	// parity reports whether the left-hand group uses L/G parity to imply a
+	// leading digit that is not itself encoded in the bars (as EAN-13 does);
+	// EAN-8 has no such digit and decodes its left-hand group with codeL
+	// alone.
+	parity bool
+
+	// checksum validates digits, the symbology's full decoded digit
+	// sequence (including any digit implied by parity), against its check
+	// digit rule.
+	checksum func(digits []int) error
+}
+
+// mainBarsLen is the number of bars making up this symbology's guards
+// and digit groups: left guard (3) + left group (4 bars/digit) + middle
+// guard (5) + right group (4 bars/digit) + right guard (3).
+func (sp symbologySpec) mainBarsLen() int {
+	return 3 + sp.leftDigits*4 + 5 + sp.rightDigits*4 + 3
+}
+
+var (
+	ean13Spec = symbologySpec{sym: EAN13, leftDigits: 6, rightDigits: 6, parity: true, checksum: validateEAN13}
+	upcaSpec  = symbologySpec{sym: UPCA, leftDigits: 6, rightDigits: 6, parity: true, checksum: validateUPCA}
+	ean8Spec  = symbologySpec{sym: EAN8, leftDigits: 4, rightDigits: 4, parity: false, checksum: validateEAN8}
+)
+
+// lineSpecs are the distinct bar layouts Decoder tries, in order, against a
+// scanline's linearized bars. UPC-A is not tried separately here: it shares
+// EAN-13's exact bar pattern and is recognized afterwards, by
+// classifyEAN13, from its implicit leading digit being 0.
+var lineSpecs = []symbologySpec{
+	ean13Spec,
+	ean8Spec,
+}
+
+// specFor returns sym's symbologySpec.
+func specFor(sym Symbology) symbologySpec {
+	switch sym {
+	case UPCA:
+		return upcaSpec
+	case EAN8:
+		return ean8Spec
+	default:
+		return ean13Spec
+	}
+}
+
+// classifyEAN13 reinterprets a 13-digit EAN-13-shaped decode as UPC-A when
+// its implicit leading digit is 0: GS1 reserves that prefix exclusively for
+// UPC-A barcodes re-encoded in EAN-13's bar pattern, so the bars alone
+// cannot otherwise tell the two symbologies apart.
+func classifyEAN13(bc Barcode) Barcode {
+	if bc.Symbology != EAN13 || len(bc.Digits) != 13 || bc.Digits[0] != 0 {
+		return bc
+	}
+	return Barcode{Symbology: UPCA, Digits: append([]int{}, bc.Digits[1:]...)}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"image"
+	"image/color"
+)
+
+// ThresholdFunc computes the binarization threshold used to turn src into
+// black/white pixels: a pixel whose grayscale value is above the
+// threshold is treated as white, at or below as black.
+type ThresholdFunc func(src image.Image) uint8
+
+// OtsuThreshold computes the threshold that maximizes the between-class
+// variance of src's grayscale histogram (Otsu's method). It adapts well
+// to images with uneven lighting, shadows or low contrast, unlike a fixed
+// threshold.
+func OtsuThreshold(src image.Image) uint8 {
+	var (
+		hist [256]int
+		b    = src.Bounds()
+	)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			hist[grayAt(src, x, y)]++
+		}
+	}
+	return otsu(hist)
+}
+
+// otsu picks the threshold t in [0,256) maximizing
+// ω₀(t)·ω₁(t)·(μ₀(t)-μ₁(t))², the between-class variance of hist split at t.
+func otsu(hist [256]int) uint8 {
+	var total int
+	for _, c := range hist {
+		total += c
+	}
+	if total == 0 {
+		return 128
+	}
+
+	var sumAll float64
+	for v, c := range hist {
+		sumAll += float64(v * c)
+	}
+
+	var (
+		wB, sumB  float64
+		best      uint8
+		bestScore float64
+	)
+	for t := 0; t < 256; t++ {
+		wB += float64(hist[t])
+		if wB == 0 {
+			continue
+		}
+		wF := float64(total) - wB
+		if wF == 0 {
+			break
+		}
+		sumB += float64(t * hist[t])
+
+		mB := sumB / wB
+		mF := (sumAll - sumB) / wF
+		d := mB - mF
+
+		score := wB * wF * d * d
+		if score > bestScore {
+			bestScore = score
+			best = uint8(t)
+		}
+	}
+	return best
+}
+
+func grayAt(src image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(src.At(x, y)).(color.Gray).Y
+}
+
+// scanLine thresholds row y of src into a raw (0=white,255=black) byte
+// line of sight.
+func scanLine(src image.Image, y int, thresh uint8) []byte {
+	b := src.Bounds()
+	line := make([]byte, b.Max.X-b.Min.X)
+	for x := b.Min.X; x < b.Max.X; x++ {
+		switch {
+		case grayAt(src, x, y) > thresh:
+			line[x-b.Min.X] = whiteBar
+		default:
+			line[x-b.Min.X] = blackBar
+		}
+	}
+	return line
+}
+
+// scanLines returns the Y coordinates of the candidate scanlines to try,
+// spread evenly across the middle third of the image (where a roughly
+// centered, horizontal barcode is expected to lie), up to ScanLines of
+// them.
+func (dec *Decoder) scanLines(b image.Rectangle) []int {
+	n := dec.ScanLines
+	if n <= 0 {
+		n = 1
+	}
+
+	h := b.Max.Y - b.Min.Y
+	lo := b.Min.Y + h/3
+	hi := b.Min.Y + 2*h/3
+	if hi <= lo {
+		return []int{b.Min.Y + h/2}
+	}
+
+	step := (hi - lo) / n
+	if step <= 0 {
+		step = 1
+	}
+
+	lines := make([]int, 0, n)
+	for y := lo; y < hi && len(lines) < n; y += step {
+		lines = append(lines, y)
+	}
+	return lines
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The isbn Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package isbn
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+func TestOtsuThreshold(t *testing.T) {
+	// a simple bimodal image: left half dark (value 20), right half light
+	// (value 230). Otsu's method should pick a threshold that separates the
+	// two modes.
+	img := image.NewGray(image.Rect(0, 0, 100, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 100; x++ {
+			v := uint8(20)
+			if x >= 50 {
+				v = 230
+			}
+			img.Set(x, y, color.Gray{Y: v})
+		}
+	}
+
+	got := OtsuThreshold(img)
+	if got < 20 || got > 230 {
+		t.Fatalf("threshold out of range: got=%d", got)
+	}
+}
+
+func TestScanMultiLine(t *testing.T) {
+	// place an encoded barcode vertically off-center within a much taller
+	// image, to exercise the multi-scanline search: a single scan at
+	// bounds.Max.Y/2 would miss it entirely.
+	digits := []int{9, 7, 8, 0, 1, 3, 4, 1, 9, 0, 4, 4, 0}
+	bc, err := Encode(digits, EncodeOptions{ModuleWidth: 3, Height: 30})
+	if err != nil {
+		t.Fatalf("could not encode barcode: %+v", err)
+	}
+
+	const (
+		yOff   = 115
+		extraH = 300
+	)
+	b := bc.Bounds()
+	img := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()+extraH))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, yOff, b.Dx(), yOff+b.Dy()), bc, image.Point{}, draw.Src)
+
+	dec := NewDecoder()
+	if err := dec.Decode(img); err != nil {
+		t.Fatalf("could not decode: %+v", err)
+	}
+
+	if got := dec.Barcode.Digits; !reflect.DeepEqual(got, digits) {
+		t.Fatalf("invalid barcode:\ngot= %v\nwant=%v", got, digits)
+	}
+}